@@ -0,0 +1,398 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html"
+)
+
+// maxCrawlConcurrency bounds how many pages the crawler fetches at once
+// within a single depth level, so a page with hundreds of same-origin links
+// can't fan out into hundreds of simultaneous requests against one host —
+// the per-host Limiter still governs pacing, but the semaphore caps how many
+// of those waits are in flight together.
+const maxCrawlConcurrency = 16
+
+// pageLinks holds everything extractPage found on a single page: the
+// same-origin anchors worth following further, and every URL-shaped string
+// (anchors, script/link/img sources, and inline-JS string literals) worth
+// fingerprinting — takeover-prone CNAMEs show up in third-party <script src>
+// just as often as in <a href>.
+type pageLinks struct {
+	links     []string
+	resources []string
+	noFollow  bool
+}
+
+var inlineURLPattern = regexp.MustCompile(`https?://[^\s'"<>\\]+`)
+
+// extractPage fetches pageURL and walks its HTML, collecting anchors to
+// crawl further plus every subresource/inline-JS URL worth fingerprinting.
+// It waits on limiter before fetching so the crawler backs off a host the
+// same way every other request path does.
+func extractPage(ctx context.Context, pageURL string, limiter Limiter) pageLinks {
+	var out pageLinks
+
+	if err := limiter.Wait(ctx, linkHost(pageURL)); err != nil {
+		return out
+	}
+
+	resp, err := httpClient.Get(pageURL)
+	if err != nil {
+		return out
+	}
+	defer resp.Body.Close()
+
+	base := resp.Request.URL
+	tokenizer := html.NewTokenizer(resp.Body)
+	inScript := false
+	for {
+		tt := tokenizer.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		token := tokenizer.Token()
+
+		switch {
+		case tt == html.StartTagToken || tt == html.SelfClosingTagToken:
+			switch token.Data {
+			case "a":
+				if href, ok := attrVal(token, "href"); ok {
+					abs := resolveURL(base, href)
+					out.links = append(out.links, abs)
+					out.resources = append(out.resources, abs)
+				}
+			case "script":
+				if src, ok := attrVal(token, "src"); ok {
+					out.resources = append(out.resources, resolveURL(base, src))
+				}
+				inScript = tt == html.StartTagToken
+			case "link":
+				if href, ok := attrVal(token, "href"); ok {
+					out.resources = append(out.resources, resolveURL(base, href))
+				}
+			case "img":
+				if src, ok := attrVal(token, "src"); ok {
+					out.resources = append(out.resources, resolveURL(base, src))
+				}
+			case "meta":
+				name, _ := attrVal(token, "name")
+				content, _ := attrVal(token, "content")
+				if strings.EqualFold(name, "robots") && strings.Contains(strings.ToLower(content), "nofollow") {
+					out.noFollow = true
+				}
+			}
+		case tt == html.EndTagToken && token.Data == "script":
+			inScript = false
+		case tt == html.TextToken && inScript:
+			out.resources = append(out.resources, inlineURLPattern.FindAllString(token.Data, -1)...)
+		}
+	}
+
+	return out
+}
+
+func attrVal(t html.Token, key string) (string, bool) {
+	for _, a := range t.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+func resolveURL(base *url.URL, ref string) string {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	if !u.IsAbs() {
+		u = base.ResolveReference(u)
+	}
+	return u.String()
+}
+
+// Crawler walks a site up to a configured depth, staying in scope (same
+// origin, unless an allow-list says otherwise) and honoring robots.txt and
+// <meta name="robots" content="nofollow">.
+type Crawler struct {
+	maxDepth   int
+	allowHosts map[string]bool
+	useSitemap bool
+	limiter    Limiter
+
+	mu          sync.Mutex
+	visited     map[string]bool
+	robotsCache map[string]*robotsRules
+}
+
+// NewCrawler builds a crawler limited to maxDepth levels beyond the seed
+// page. When allowHosts is empty, scope is restricted to the seed's own
+// host (same-origin); otherwise only hosts in allowHosts are followed.
+// Every fetch it makes goes through limiter so the crawler obeys the same
+// per-host pacing as the fingerprint engine.
+func NewCrawler(maxDepth int, allowHosts []string, useSitemap bool, limiter Limiter) *Crawler {
+	allow := make(map[string]bool, len(allowHosts))
+	for _, h := range allowHosts {
+		allow[strings.ToLower(h)] = true
+	}
+	return &Crawler{
+		maxDepth:    maxDepth,
+		allowHosts:  allow,
+		useSitemap:  useSitemap,
+		limiter:     limiter,
+		visited:     make(map[string]bool),
+		robotsCache: make(map[string]*robotsRules),
+	}
+}
+
+func (c *Crawler) inScope(host, seedHost string) bool {
+	if len(c.allowHosts) > 0 {
+		return c.allowHosts[strings.ToLower(host)]
+	}
+	return strings.EqualFold(host, seedHost)
+}
+
+func (c *Crawler) markVisited(pageURL string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.visited[pageURL] {
+		return false
+	}
+	c.visited[pageURL] = true
+	return true
+}
+
+func (c *Crawler) robotsFor(ctx context.Context, origin string) *robotsRules {
+	c.mu.Lock()
+	if r, ok := c.robotsCache[origin]; ok {
+		c.mu.Unlock()
+		return r
+	}
+	c.mu.Unlock()
+
+	r := fetchRobots(ctx, origin, c.limiter)
+	c.mu.Lock()
+	c.robotsCache[origin] = r
+	c.mu.Unlock()
+	return r
+}
+
+// Crawl walks seed breadth-first up to maxDepth and returns the deduped set
+// of every resource URL worth fingerprinting. Fetches within a depth level
+// run concurrently up to maxCrawlConcurrency, each one pacing itself against
+// c.limiter.
+func (c *Crawler) Crawl(ctx context.Context, seed string) []string {
+	seedHost := linkHost(seed)
+	queue := []string{seed}
+	if c.useSitemap {
+		queue = append(queue, c.seedFromSitemap(ctx, seed)...)
+	}
+
+	seen := make(map[string]bool)
+	var resources []string
+
+	for depth := 0; depth <= c.maxDepth && len(queue) > 0; depth++ {
+		var next []string
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, maxCrawlConcurrency)
+
+		for _, pageURL := range queue {
+			if !c.markVisited(pageURL) {
+				continue
+			}
+			host := linkHost(pageURL)
+			if host == "" || !c.inScope(host, seedHost) {
+				continue
+			}
+			origin := originOf(pageURL)
+			if origin != "" && !c.robotsFor(ctx, origin).allows(pathOf(pageURL)) {
+				continue
+			}
+
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(u string) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				page := extractPage(ctx, u, c.limiter)
+
+				mu.Lock()
+				defer mu.Unlock()
+				for _, r := range page.resources {
+					if !seen[r] {
+						seen[r] = true
+						resources = append(resources, r)
+					}
+				}
+				if !page.noFollow {
+					next = append(next, page.links...)
+				}
+			}(pageURL)
+		}
+
+		wg.Wait()
+		queue = next
+	}
+
+	return resources
+}
+
+func (c *Crawler) seedFromSitemap(ctx context.Context, seed string) []string {
+	origin := originOf(seed)
+	if origin == "" {
+		return nil
+	}
+	candidates := []string{origin + "/sitemap.xml"}
+	candidates = append(candidates, c.robotsFor(ctx, origin).sitemaps...)
+
+	var seeds []string
+	for _, sm := range candidates {
+		seeds = append(seeds, fetchSitemapURLs(ctx, sm, c.limiter)...)
+	}
+	return seeds
+}
+
+// ------------------ robots.txt ------------------ //
+type robotsRules struct {
+	disallow []string
+	sitemaps []string
+}
+
+func (r *robotsRules) allows(path string) bool {
+	for _, d := range r.disallow {
+		if d != "" && strings.HasPrefix(path, d) {
+			return false
+		}
+	}
+	return true
+}
+
+// fetchRobots parses the Disallow rules that apply to User-agent: * (this
+// scanner doesn't identify itself with a distinct UA in robots.txt, so the
+// wildcard group is the one that governs it) plus any Sitemap: directives.
+func fetchRobots(ctx context.Context, origin string, limiter Limiter) *robotsRules {
+	rules := &robotsRules{}
+	if err := limiter.Wait(ctx, linkHost(origin)); err != nil {
+		return rules
+	}
+	resp, err := httpClient.Get(origin + "/robots.txt")
+	if err != nil {
+		return rules
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return rules
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return rules
+	}
+
+	applies := false
+	for _, line := range strings.Split(string(body), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, val, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		val = strings.TrimSpace(val)
+
+		switch key {
+		case "user-agent":
+			applies = val == "*"
+		case "disallow":
+			if applies {
+				rules.disallow = append(rules.disallow, val)
+			}
+		case "sitemap":
+			rules.sitemaps = append(rules.sitemaps, val)
+		}
+	}
+	return rules
+}
+
+// ------------------ sitemap.xml ------------------ //
+type sitemapURLSet struct {
+	XMLName xml.Name        `xml:"urlset"`
+	URLs    []sitemapURLEnt `xml:"url"`
+}
+
+type sitemapURLEnt struct {
+	Loc string `xml:"loc"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name             `xml:"sitemapindex"`
+	Sitemaps []sitemapIndexSitemp `xml:"sitemap"`
+}
+
+type sitemapIndexSitemp struct {
+	Loc string `xml:"loc"`
+}
+
+func fetchSitemapURLs(ctx context.Context, sitemapURL string, limiter Limiter) []string {
+	if err := limiter.Wait(ctx, linkHost(sitemapURL)); err != nil {
+		return nil
+	}
+	resp, err := httpClient.Get(sitemapURL)
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil
+	}
+
+	var urlset sitemapURLSet
+	if err := xml.Unmarshal(body, &urlset); err == nil && len(urlset.URLs) > 0 {
+		out := make([]string, 0, len(urlset.URLs))
+		for _, u := range urlset.URLs {
+			out = append(out, u.Loc)
+		}
+		return out
+	}
+
+	var index sitemapIndex
+	if err := xml.Unmarshal(body, &index); err == nil && len(index.Sitemaps) > 0 {
+		var out []string
+		for _, s := range index.Sitemaps {
+			out = append(out, fetchSitemapURLs(ctx, s.Loc, limiter)...)
+		}
+		return out
+	}
+
+	return nil
+}
+
+func originOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+func pathOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "/"
+	}
+	if u.Path == "" {
+		return "/"
+	}
+	return u.Path
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// spyLimiter wraps a real HostLimiter but counts Wait calls, so tests can
+// assert the crawler actually paces its fetches through it instead of
+// calling httpClient directly.
+type spyLimiter struct {
+	*HostLimiter
+	waits int64
+}
+
+func newSpyLimiter() *spyLimiter {
+	return &spyLimiter{HostLimiter: NewHostLimiter(1000, time.Second)}
+}
+
+func (s *spyLimiter) Wait(ctx context.Context, host string) error {
+	atomic.AddInt64(&s.waits, 1)
+	return s.HostLimiter.Wait(ctx, host)
+}
+
+func newLoopbackServer(t *testing.T, addr string, handler http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Skipf("cannot bind %s in this sandbox: %v", addr, err)
+	}
+	srv := httptest.NewUnstartedServer(handler)
+	srv.Listener.Close()
+	srv.Listener = lis
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestCrawlDedupsRepeatedLinks(t *testing.T) {
+	srv := newLoopbackServer(t, "127.0.0.1:0", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<a href="/b">b</a><a href="/b">b again</a><a href="/c">c</a>`)
+	})
+
+	limiter := newSpyLimiter()
+	crawler := NewCrawler(0, nil, false, limiter)
+
+	got := crawler.Crawl(context.Background(), srv.URL)
+	sort.Strings(got)
+
+	want := []string{srv.URL + "/b", srv.URL + "/c"}
+	sort.Strings(want)
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("Crawl resources = %v, want deduped %v", got, want)
+	}
+	if atomic.LoadInt64(&limiter.waits) == 0 {
+		t.Fatal("Crawl should route its fetch through the limiter")
+	}
+}
+
+func TestCrawlHonorsDepth(t *testing.T) {
+	var hits int64
+	srv := newLoopbackServer(t, "127.0.0.1:0", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		n := atomic.AddInt64(&hits, 1)
+		fmt.Fprintf(w, `<a href="/page%d">next</a>`, n+1)
+	})
+
+	limiter := newSpyLimiter()
+	crawler := NewCrawler(1, nil, false, limiter)
+	crawler.Crawl(context.Background(), srv.URL)
+
+	// depth 1 means the seed plus one hop beyond it: the seed page and the
+	// one page it links to, no further.
+	if got := atomic.LoadInt64(&hits); got != 2 {
+		t.Fatalf("fetched %d pages, want exactly 2 for depth=1", got)
+	}
+}
+
+func TestCrawlHonorsMetaNoFollow(t *testing.T) {
+	var followedHit int32
+	srv := newLoopbackServer(t, "127.0.0.1:0", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<meta name="robots" content="nofollow"><a href="/never">never</a>`)
+		case "/never":
+			atomic.StoreInt32(&followedHit, 1)
+			fmt.Fprint(w, "should not be reached")
+		}
+	})
+
+	limiter := newSpyLimiter()
+	crawler := NewCrawler(3, nil, false, limiter)
+	crawler.Crawl(context.Background(), srv.URL)
+
+	if atomic.LoadInt32(&followedHit) != 0 {
+		t.Fatal("a page with <meta name=robots content=nofollow> must not be followed")
+	}
+}
+
+func TestCrawlHonorsRobotsDisallow(t *testing.T) {
+	var blockedHit int32
+	srv := newLoopbackServer(t, "127.0.0.1:0", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			fmt.Fprint(w, "User-agent: *\nDisallow: /private\n")
+		case "/":
+			fmt.Fprint(w, `<a href="/private/secret">secret</a>`)
+		case "/private/secret":
+			atomic.StoreInt32(&blockedHit, 1)
+			fmt.Fprint(w, "should not be reached")
+		}
+	})
+
+	limiter := newSpyLimiter()
+	crawler := NewCrawler(3, nil, false, limiter)
+	crawler.Crawl(context.Background(), srv.URL)
+
+	if atomic.LoadInt32(&blockedHit) != 0 {
+		t.Fatal("a path disallowed by robots.txt must not be fetched")
+	}
+}
+
+func TestCrawlStaysInScopeByDefault(t *testing.T) {
+	var external int32
+	otherSrv := newLoopbackServer(t, "127.0.0.2:0", func(w http.ResponseWriter, r *http.Request) {
+		atomic.StoreInt32(&external, 1)
+	})
+
+	seedSrv := newLoopbackServer(t, "127.0.0.1:0", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<a href="%s/elsewhere">elsewhere</a>`, otherSrv.URL)
+	})
+
+	limiter := newSpyLimiter()
+	crawler := NewCrawler(2, nil, false, limiter)
+	got := crawler.Crawl(context.Background(), seedSrv.URL)
+
+	if atomic.LoadInt32(&external) != 0 {
+		t.Fatal("same-origin scope (no --allow-hosts) must not follow a link to another host")
+	}
+
+	found := false
+	for _, r := range got {
+		if r == otherSrv.URL+"/elsewhere" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("the external link should still be reported as a fingerprint-worthy resource, just not followed")
+	}
+}
+
+func TestCrawlAllowHostsPermitsExtraHosts(t *testing.T) {
+	var external int32
+	otherSrv := newLoopbackServer(t, "127.0.0.2:0", func(w http.ResponseWriter, r *http.Request) {
+		atomic.StoreInt32(&external, 1)
+	})
+
+	seedSrv := newLoopbackServer(t, "127.0.0.1:0", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<a href="%s/elsewhere">elsewhere</a>`, otherSrv.URL)
+	})
+
+	// allowHosts is an explicit list, not an addition to same-origin scope —
+	// the seed's own host has to be named too, or the seed page itself never
+	// gets fetched.
+	allowHosts := []string{linkHost(seedSrv.URL), linkHost(otherSrv.URL)}
+
+	limiter := newSpyLimiter()
+	crawler := NewCrawler(2, allowHosts, false, limiter)
+	crawler.Crawl(context.Background(), seedSrv.URL)
+
+	if atomic.LoadInt32(&external) == 0 {
+		t.Fatal("a host named in --allow-hosts should be followed")
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// DNSRecord is the resolved state of a single hostname, gathered before any
+// HTTP fingerprinting happens so detectService can key off the CNAME target
+// instead of just the URL string.
+type DNSRecord struct {
+	Host           string
+	CNAME          string
+	A              []string
+	NS             []string
+	Dangling       bool
+	DanglingReason string
+}
+
+// DNSResolver wraps net.Resolver with the configurable nameservers and
+// timeout the scanner's DNS subsystem needs.
+type DNSResolver struct {
+	resolver *net.Resolver
+	timeout  time.Duration
+}
+
+// NewDNSResolver builds a resolver that talks to the given nameservers
+// (host:port, e.g. "1.1.1.1:53") instead of the system default when servers
+// is non-empty.
+func NewDNSResolver(servers []string, timeout time.Duration) *DNSResolver {
+	r := &net.Resolver{}
+	if len(servers) > 0 {
+		r.PreferGo = true
+		r.Dial = func(ctx context.Context, network, address string) (net.Conn, error) {
+			var lastErr error
+			for _, server := range servers {
+				d := net.Dialer{Timeout: timeout}
+				conn, err := d.DialContext(ctx, network, server)
+				if err == nil {
+					return conn, nil
+				}
+				lastErr = err
+			}
+			return nil, lastErr
+		}
+	}
+	return &DNSResolver{resolver: r, timeout: timeout}
+}
+
+// Resolve looks up the CNAME, A, and NS records for host and flags it as
+// dangling when the CNAME target doesn't resolve, the NS delegation points
+// at nameservers that don't exist, or the CNAME points at a service whose
+// fingerprint already reads as takeover-vulnerable.
+func (d *DNSResolver) Resolve(host string, engine *FingerprintEngine, limiter Limiter) DNSRecord {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	rec := DNSRecord{Host: host}
+
+	if cname, err := d.resolver.LookupCNAME(ctx, host); err == nil {
+		rec.CNAME = strings.TrimSuffix(cname, ".")
+	}
+
+	if addrs, err := d.resolver.LookupHost(ctx, host); err != nil {
+		if isNXDOMAIN(err) {
+			rec.Dangling = true
+			rec.DanglingReason = "NXDOMAIN on target"
+		}
+	} else {
+		rec.A = addrs
+	}
+
+	if ns, err := d.resolver.LookupNS(ctx, host); err == nil {
+		for _, n := range ns {
+			rec.NS = append(rec.NS, strings.TrimSuffix(n.Host, "."))
+		}
+		for _, n := range rec.NS {
+			if _, err := d.resolver.LookupHost(ctx, n); err != nil && isNXDOMAIN(err) {
+				rec.Dangling = true
+				rec.DanglingReason = fmt.Sprintf("NS delegation to unregistered nameserver %s", n)
+			}
+		}
+	}
+
+	if !rec.Dangling && rec.CNAME != "" && engine != nil {
+		if res := engine.Detect(ctx, "https://"+rec.CNAME, rec.CNAME, limiter); res.Status == string(ConfidenceVulnerable) {
+			rec.Dangling = true
+			rec.DanglingReason = fmt.Sprintf("CNAME target %s fingerprints as %s", rec.CNAME, res.Type)
+		}
+	}
+
+	return rec
+}
+
+func isNXDOMAIN(err error) bool {
+	dnsErr, ok := err.(*net.DNSError)
+	return ok && dnsErr.IsNotFound
+}
+
+// dnsOnlyScan resolves each hostname in hosts and reports dangling records
+// without ever making an HTTP request, for the --dns-only CLI mode. hosts are
+// sites.txt lines, i.e. full URLs, so each is reduced to a bare hostname with
+// linkHost before it's handed to the resolver.
+func dnsOnlyScan(hosts []string, resolver *DNSResolver, engine *FingerprintEngine, limiter Limiter, log *Logger) []DNSRecord {
+	records := make([]DNSRecord, 0, len(hosts))
+	for _, rawHost := range hosts {
+		host := linkHost(rawHost)
+		if host == "" {
+			host = rawHost
+		}
+		rec := resolver.Resolve(host, engine, limiter)
+		records = append(records, rec)
+		if rec.Dangling {
+			log.Warnf("[dangling] %s: %s", rec.Host, rec.DanglingReason)
+		}
+	}
+	return records
+}
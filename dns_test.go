@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestDetectFollowsCNAMEAsURL guards against passing a bare CNAME target
+// (e.g. "fake-app.herokuapp.com") straight into http.NewRequest, which fails
+// client-side with "unsupported protocol scheme" and silently prevents
+// Resolve's dangling-CNAME fingerprint check from ever firing.
+func TestDetectFollowsCNAMEAsURL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("No such app"))
+	}))
+	defer srv.Close()
+
+	origClient := httpClient
+	httpClient = srv.Client()
+	defer func() { httpClient = origClient }()
+
+	engine := &FingerprintEngine{rules: []Fingerprint{{
+		Service:        "herokuapp",
+		CNAME:          []string{"herokuapp.com"},
+		ExpectedStatus: []int{http.StatusNotFound},
+		BodyMatch:      "No such app",
+		Confidence:     ConfidenceVulnerable,
+	}}}
+	limiter := NewHostLimiter(1000, time.Second)
+
+	cname := "fake-app.herokuapp.com"
+	res := engine.Detect(context.Background(), srv.URL, cname, limiter)
+
+	if res.Status != string(ConfidenceVulnerable) {
+		t.Fatalf("Detect(%q, %q) = status %q, want %q", srv.URL, cname, res.Status, ConfidenceVulnerable)
+	}
+}
+
+// TestDetectRejectsSchemelessLink documents the failure mode the bug above
+// produced: a bare hostname with no scheme can't be dialed at all.
+func TestDetectRejectsSchemelessLink(t *testing.T) {
+	engine := &FingerprintEngine{rules: []Fingerprint{{
+		Service:    "herokuapp",
+		CNAME:      []string{"herokuapp.com"},
+		Confidence: ConfidenceVulnerable,
+	}}}
+	limiter := NewHostLimiter(1000, time.Second)
+
+	res := engine.Detect(context.Background(), "fake-app.herokuapp.com", "fake-app.herokuapp.com", limiter)
+
+	if res.Status != "connection_error" {
+		t.Fatalf("Detect on a schemeless link = status %q, want %q", res.Status, "connection_error")
+	}
+}
+
+// TestDnsOnlyScanStripsURLScheme guards against passing sites.txt's full
+// URLs (e.g. "https://example.com") straight to the resolver as if they
+// were bare hostnames — net.Resolver.Lookup* doesn't accept a scheme, and
+// the failure used to get misreported as a dangling NXDOMAIN record.
+func TestDnsOnlyScanStripsURLScheme(t *testing.T) {
+	resolver := NewDNSResolver(nil, 50*time.Millisecond)
+	log := NewLogger(LogError)
+	limiter := NewHostLimiter(1000, time.Second)
+
+	records := dnsOnlyScan([]string{"https://example.com/path"}, resolver, nil, limiter, log)
+
+	if len(records) != 1 {
+		t.Fatalf("dnsOnlyScan returned %d records, want 1", len(records))
+	}
+	if records[0].Host != "example.com" {
+		t.Fatalf("dnsOnlyScan resolved host %q, want the scheme/path stripped to %q", records[0].Host, "example.com")
+	}
+}
+
+func TestIsNXDOMAIN(t *testing.T) {
+	notFound := &net.DNSError{Err: "no such host", IsNotFound: true}
+	if !isNXDOMAIN(notFound) {
+		t.Fatal("expected isNXDOMAIN(notFound) to be true")
+	}
+
+	other := &net.DNSError{Err: "timeout", IsTimeout: true}
+	if isNXDOMAIN(other) {
+		t.Fatal("expected isNXDOMAIN(timeout) to be false")
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Confidence is the takeover likelihood a fingerprint rule reports when it
+// matches a response.
+type Confidence string
+
+const (
+	ConfidenceVulnerable Confidence = "vulnerable"
+	ConfidenceEdgeCase   Confidence = "edge case"
+	ConfidenceNotVuln    Confidence = "not vulnerable"
+)
+
+// Fingerprint describes how to recognize a single dangling-service response.
+// Rules are loaded from an external YAML file so new services can be added
+// without recompiling the scanner.
+type Fingerprint struct {
+	Service        string     `yaml:"service"`
+	HostSuffix     []string   `yaml:"host_suffix"`
+	CNAME          []string   `yaml:"cname"`
+	Method         string     `yaml:"method"`
+	ExpectedStatus []int      `yaml:"expected_status"`
+	BodyMatch      string     `yaml:"body_match"`
+	BodyRegex      string     `yaml:"body_regex"`
+	Confidence     Confidence `yaml:"confidence"`
+
+	bodyRegex *regexp.Regexp
+}
+
+// FingerprintEngine holds the loaded rule set and matches links/CNAMEs
+// against it in order, returning the first hit.
+type FingerprintEngine struct {
+	rules []Fingerprint
+}
+
+// LoadFingerprints reads and compiles the fingerprint database at path.
+func LoadFingerprints(path string) (*FingerprintEngine, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading fingerprints file: %w", err)
+	}
+
+	var rules []Fingerprint
+	if err := yaml.Unmarshal(raw, &rules); err != nil {
+		return nil, fmt.Errorf("parsing fingerprints file: %w", err)
+	}
+
+	for i := range rules {
+		if rules[i].BodyRegex == "" {
+			continue
+		}
+		re, err := regexp.Compile(rules[i].BodyRegex)
+		if err != nil {
+			return nil, fmt.Errorf("compiling body_regex for %q: %w", rules[i].Service, err)
+		}
+		rules[i].bodyRegex = re
+	}
+
+	return &FingerprintEngine{rules: rules}, nil
+}
+
+// candidates returns the rules whose host_suffix or cname patterns match the
+// link (or its resolved CNAME target, when known).
+func (e *FingerprintEngine) candidates(link, cnameTarget string) []Fingerprint {
+	var out []Fingerprint
+	for _, r := range e.rules {
+		if matchesHost(r.HostSuffix, link) || matchesHost(r.CNAME, cnameTarget) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func matchesHost(patterns []string, host string) bool {
+	if host == "" {
+		return false
+	}
+	for _, p := range patterns {
+		if strings.HasPrefix(p, "^") {
+			if re, err := regexp.Compile(p); err == nil && re.MatchString(host) {
+				return true
+			}
+			continue
+		}
+		if strings.Contains(host, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Detect fetches link, matches it against the candidate rules, and returns a
+// Result carrying the matched rule's service name and confidence. cnameTarget
+// may be empty when no DNS resolution was performed. limiter is consulted
+// before the request and told about throttling/success afterward so repeated
+// hits against the same host back off instead of hammering it.
+func (e *FingerprintEngine) Detect(ctx context.Context, link, cnameTarget string, limiter Limiter) Result {
+	candidates := e.candidates(link, cnameTarget)
+	if len(candidates) == 0 {
+		return Result{Type: "unknown", Status: "skipped", URL: link}
+	}
+
+	method := "GET"
+	for _, r := range candidates {
+		if r.Method != "" {
+			method = r.Method
+			break
+		}
+	}
+
+	host := linkHost(link)
+	if err := limiter.Wait(ctx, host); err != nil {
+		return Result{Type: "unknown", Status: "connection_error", URL: link}
+	}
+
+	req, err := http.NewRequest(method, link, nil)
+	if err != nil {
+		return Result{Type: "unknown", Status: "invalid_url", URL: link}
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return Result{Type: "unknown", Status: "connection_error", URL: link}
+	}
+	defer resp.Body.Close()
+
+	if isThrottleStatus(resp.StatusCode) {
+		limiter.Throttle(host, retryAfterFromResponse(resp))
+		return Result{Type: candidates[0].Service, Status: "rate_limited", URL: link}
+	}
+	limiter.Reset(host)
+
+	body := readBody(resp)
+
+	for _, r := range candidates {
+		if !statusMatches(r.ExpectedStatus, resp.StatusCode) {
+			continue
+		}
+		if r.BodyMatch != "" && !strings.Contains(body, r.BodyMatch) {
+			continue
+		}
+		if r.bodyRegex != nil && !r.bodyRegex.MatchString(body) {
+			continue
+		}
+		return Result{
+			Type:       r.Service,
+			Status:     string(r.Confidence),
+			URL:        link,
+			Rule:       r.Service,
+			Confidence: string(r.Confidence),
+			BodyHash:   hashBody(body),
+		}
+	}
+
+	return Result{Type: candidates[0].Service, Status: "ok", URL: link, BodyHash: hashBody(body)}
+}
+
+func statusMatches(expected []int, got int) bool {
+	if len(expected) == 0 {
+		return true
+	}
+	for _, s := range expected {
+		if s == got {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package main
+
+import "testing"
+
+func TestMatchesHost(t *testing.T) {
+	cases := []struct {
+		name     string
+		patterns []string
+		host     string
+		want     bool
+	}{
+		{"empty patterns never match", nil, "example.s3.amazonaws.com", false},
+		{"empty host never matches", []string{".s3.amazonaws.com"}, "", false},
+		{"substring match", []string{".s3.amazonaws.com"}, "bucket.s3.amazonaws.com", true},
+		{"substring miss", []string{".s3.amazonaws.com"}, "example.com", false},
+		{"regex match", []string{"^[a-z0-9-]+\\.ngrok\\.io$"}, "abc123.ngrok.io", true},
+		{"regex miss", []string{"^[a-z0-9-]+\\.ngrok\\.io$"}, "evil.ngrok.io.attacker.com", false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := matchesHost(c.patterns, c.host); got != c.want {
+				t.Errorf("matchesHost(%v, %q) = %v, want %v", c.patterns, c.host, got, c.want)
+			}
+		})
+	}
+}
+
+// TestCandidatesRequiresAMatchPattern guards against fingerprint entries that
+// declare neither host_suffix nor cname: candidates() must never surface a
+// rule nothing can actually select.
+func TestCandidatesRequiresAMatchPattern(t *testing.T) {
+	engine := &FingerprintEngine{rules: []Fingerprint{
+		{Service: "no-pattern", Confidence: ConfidenceVulnerable},
+		{Service: "heroku", HostSuffix: []string{"herokuapp.com"}, Confidence: ConfidenceVulnerable},
+	}}
+
+	got := engine.candidates("https://app.herokuapp.com", "")
+	if len(got) != 1 || got[0].Service != "heroku" {
+		t.Fatalf("candidates() = %v, want only the heroku rule", got)
+	}
+
+	if got := engine.candidates("https://example.com", ""); len(got) != 0 {
+		t.Fatalf("candidates() for an unrelated link = %v, want none", got)
+	}
+}
+
+func TestStatusMatches(t *testing.T) {
+	if !statusMatches(nil, 404) {
+		t.Error("statusMatches(nil, 404) should default to true")
+	}
+	if !statusMatches([]int{404, 403}, 403) {
+		t.Error("statusMatches([404,403], 403) should be true")
+	}
+	if statusMatches([]int{404}, 200) {
+		t.Error("statusMatches([404], 200) should be false")
+	}
+}
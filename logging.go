@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// LogLevel gates which log calls actually print, configured via --log-level.
+type LogLevel int
+
+const (
+	LogDebug LogLevel = iota
+	LogInfo
+	LogWarn
+	LogError
+)
+
+func parseLogLevel(s string) LogLevel {
+	switch strings.ToLower(s) {
+	case "debug":
+		return LogDebug
+	case "warn", "warning":
+		return LogWarn
+	case "error":
+		return LogError
+	default:
+		return LogInfo
+	}
+}
+
+// Logger is a minimal leveled logger. It replaces the scanner's former
+// ad-hoc fmt.Println calls so output can be filtered by severity and always
+// lands on stderr, leaving stdout free for piping results.
+type Logger struct {
+	level LogLevel
+}
+
+func NewLogger(level LogLevel) *Logger {
+	return &Logger{level: level}
+}
+
+func (l *Logger) log(level LogLevel, label, format string, args ...interface{}) {
+	if level < l.level {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%s [%s] %s\n", time.Now().Format(time.RFC3339), label, fmt.Sprintf(format, args...))
+}
+
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	l.log(LogDebug, "DEBUG", format, args...)
+}
+func (l *Logger) Infof(format string, args ...interface{}) { l.log(LogInfo, "INFO", format, args...) }
+func (l *Logger) Warnf(format string, args ...interface{}) { l.log(LogWarn, "WARN", format, args...) }
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.log(LogError, "ERROR", format, args...)
+}
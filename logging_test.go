@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func captureStderr(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("os.Pipe: %v", err)
+	}
+	orig := os.Stderr
+	os.Stderr = w
+	fn()
+	w.Close()
+	os.Stderr = orig
+
+	var buf bytes.Buffer
+	io.Copy(&buf, r)
+	return buf.String()
+}
+
+func TestParseLogLevel(t *testing.T) {
+	cases := map[string]LogLevel{
+		"debug":   LogDebug,
+		"DEBUG":   LogDebug,
+		"info":    LogInfo,
+		"":        LogInfo,
+		"bogus":   LogInfo,
+		"warn":    LogWarn,
+		"warning": LogWarn,
+		"error":   LogError,
+	}
+	for in, want := range cases {
+		if got := parseLogLevel(in); got != want {
+			t.Errorf("parseLogLevel(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestLoggerGatesByLevel(t *testing.T) {
+	log := NewLogger(LogWarn)
+
+	out := captureStderr(t, func() {
+		log.Debugf("debug message")
+		log.Infof("info message")
+		log.Warnf("warn message")
+		log.Errorf("error message")
+	})
+
+	if strings.Contains(out, "debug message") {
+		t.Error("a Warn-level logger should suppress Debugf output")
+	}
+	if strings.Contains(out, "info message") {
+		t.Error("a Warn-level logger should suppress Infof output")
+	}
+	if !strings.Contains(out, "warn message") {
+		t.Error("a Warn-level logger should print Warnf output")
+	}
+	if !strings.Contains(out, "error message") {
+		t.Error("a Warn-level logger should print Errorf output")
+	}
+}
+
+func TestLoggerDebugLevelPrintsEverything(t *testing.T) {
+	log := NewLogger(LogDebug)
+
+	out := captureStderr(t, func() {
+		log.Debugf("all the way down")
+	})
+
+	if !strings.Contains(out, "all the way down") {
+		t.Error("a Debug-level logger should print Debugf output")
+	}
+	if !strings.Contains(out, "[DEBUG]") {
+		t.Errorf("expected a [DEBUG] label in output, got %q", out)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"sync/atomic"
+
+	"github.com/cheggaaa/pb/v3"
+)
+
+// ScanProgress drives a cheggaaa/pb bar showing sites scanned, links
+// checked, findings, and rate-limit hits, so long runs over large site
+// lists have a live indicator instead of a silent wait.
+type ScanProgress struct {
+	bar           *pb.ProgressBar
+	linksChecked  int64
+	findings      int64
+	rateLimitHits int64
+}
+
+func NewScanProgress(totalSites int) *ScanProgress {
+	tmpl := `{{ green "sites:" }} {{counters . }} {{ bar . }} {{percent . }} ` +
+		`links:{{string . "links"}} findings:{{string . "findings"}} rate_limited:{{string . "rate_limited"}}`
+	bar := pb.ProgressBarTemplate(tmpl).Start(totalSites)
+	bar.Set("links", 0)
+	bar.Set("findings", 0)
+	bar.Set("rate_limited", 0)
+	return &ScanProgress{bar: bar}
+}
+
+func (p *ScanProgress) SiteScanned() {
+	p.bar.Increment()
+}
+
+func (p *ScanProgress) LinkChecked(r Result) {
+	atomic.AddInt64(&p.linksChecked, 1)
+	switch r.Status {
+	case "possible_takeover", string(ConfidenceVulnerable):
+		atomic.AddInt64(&p.findings, 1)
+	case "rate_limited":
+		atomic.AddInt64(&p.rateLimitHits, 1)
+	}
+	p.bar.Set("links", atomic.LoadInt64(&p.linksChecked))
+	p.bar.Set("findings", atomic.LoadInt64(&p.findings))
+	p.bar.Set("rate_limited", atomic.LoadInt64(&p.rateLimitHits))
+}
+
+// Finish stops the bar so the shell prompt isn't left stuck on the same
+// line; it's safe to call from the SIGINT handler for a graceful teardown.
+func (p *ScanProgress) Finish() {
+	p.bar.Finish()
+}
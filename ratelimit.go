@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Limiter is the context-aware rate limiter shared by every request path
+// (fingerprint engine, GitHub API, Chrome Web Store) so they all back off a
+// throttled host the same way instead of each rolling their own sleep.
+type Limiter interface {
+	// Wait blocks until host is allowed to make another request, or ctx is
+	// done.
+	Wait(ctx context.Context, host string) error
+	// Throttle records that host just rejected a request and sets the
+	// backoff window a subsequent Wait will honor. retryAfter overrides the
+	// computed exponential backoff when the upstream gave an explicit hint.
+	Throttle(host string, retryAfter time.Duration)
+	// Reset clears a host's backoff state after a successful response.
+	Reset(host string)
+}
+
+// initialBackoff is the starting point for the exponential backoff; it
+// doubles (capped at backoffMax) on each consecutive throttle.
+const initialBackoff = 10 * time.Millisecond
+
+// HostLimiter enforces a per-host token-bucket request rate and layers
+// exponential backoff with full jitter on top, triggered by 429/403/5xx
+// responses or explicit Retry-After/X-RateLimit-Remaining hints.
+type HostLimiter struct {
+	rps        float64
+	backoffMax time.Duration
+
+	mu       sync.Mutex
+	buckets  map[string]*rate.Limiter
+	attempts map[string]int
+	until    map[string]time.Time
+}
+
+// NewHostLimiter builds a limiter allowing rps requests per second to any
+// single host, with backoff capped at backoffMax.
+func NewHostLimiter(rps float64, backoffMax time.Duration) *HostLimiter {
+	return &HostLimiter{
+		rps:        rps,
+		backoffMax: backoffMax,
+		buckets:    make(map[string]*rate.Limiter),
+		attempts:   make(map[string]int),
+		until:      make(map[string]time.Time),
+	}
+}
+
+func (h *HostLimiter) bucketFor(host string) *rate.Limiter {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	b, ok := h.buckets[host]
+	if !ok {
+		b = rate.NewLimiter(rate.Limit(h.rps), 1)
+		h.buckets[host] = b
+	}
+	return b
+}
+
+func (h *HostLimiter) Wait(ctx context.Context, host string) error {
+	h.mu.Lock()
+	until := h.until[host]
+	h.mu.Unlock()
+
+	if wait := time.Until(until); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return h.bucketFor(host).Wait(ctx)
+}
+
+func (h *HostLimiter) Throttle(host string, retryAfter time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.attempts[host]++
+	wait := retryAfter
+	if wait <= 0 {
+		wait = backoffWithJitter(h.attempts[host], h.backoffMax)
+	}
+	h.until[host] = time.Now().Add(wait)
+}
+
+func (h *HostLimiter) Reset(host string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.attempts, host)
+	delete(h.until, host)
+}
+
+// backoffWithJitter computes a full-jitter exponential backoff: a random
+// duration between 0 and min(backoffMax, initialBackoff*2^(attempt-1)).
+func backoffWithJitter(attempt int, backoffMax time.Duration) time.Duration {
+	backoff := float64(initialBackoff) * math.Pow(2, float64(attempt-1))
+	if backoff > float64(backoffMax) {
+		backoff = float64(backoffMax)
+	}
+	return time.Duration(rand.Float64() * backoff)
+}
+
+// retryAfterFromResponse reads Retry-After / X-RateLimit-Remaining hints off
+// a response so the caller can pass a precise backoff to Throttle instead of
+// relying purely on the computed exponential.
+func retryAfterFromResponse(resp *http.Response) time.Duration {
+	if v := resp.Header.Get("Retry-After"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil {
+			return time.Duration(secs) * time.Second
+		}
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if resetAt := resp.Header.Get("X-RateLimit-Reset"); resetAt != "" {
+			if epoch, err := strconv.ParseInt(resetAt, 10, 64); err == nil {
+				if d := time.Until(time.Unix(epoch, 0)); d > 0 {
+					return d
+				}
+			}
+		}
+	}
+	return 0
+}
+
+func isThrottleStatus(code int) bool {
+	return code == http.StatusForbidden || code == http.StatusTooManyRequests || code >= 500
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBackoffWithJitterIsBoundedAndGrows(t *testing.T) {
+	backoffMax := 10 * time.Second
+
+	for attempt := 1; attempt <= 10; attempt++ {
+		d := backoffWithJitter(attempt, backoffMax)
+		if d < 0 || d > backoffMax {
+			t.Fatalf("backoffWithJitter(%d, %s) = %s, want within [0, %s]", attempt, backoffMax, d, backoffMax)
+		}
+	}
+
+	// Once the exponential term exceeds backoffMax, the ceiling should win
+	// regardless of how many further attempts accrue.
+	far := backoffWithJitter(64, backoffMax)
+	if far > backoffMax {
+		t.Fatalf("backoffWithJitter(64, %s) = %s, want capped at %s", backoffMax, far, backoffMax)
+	}
+}
+
+func TestHostLimiterThrottleThenReset(t *testing.T) {
+	h := NewHostLimiter(1000, time.Second)
+
+	h.Throttle("example.com", 50*time.Millisecond)
+	h.mu.Lock()
+	until := h.until["example.com"]
+	h.mu.Unlock()
+	if time.Until(until) <= 0 {
+		t.Fatal("Throttle should set a future until time when given an explicit retryAfter")
+	}
+
+	h.Reset("example.com")
+	h.mu.Lock()
+	_, stillThrottled := h.until["example.com"]
+	h.mu.Unlock()
+	if stillThrottled {
+		t.Fatal("Reset should clear a host's backoff state")
+	}
+}
+
+func TestRetryAfterFromResponse(t *testing.T) {
+	resp := &http.Response{Header: make(http.Header)}
+	resp.Header.Set("Retry-After", "2")
+	if got := retryAfterFromResponse(resp); got != 2*time.Second {
+		t.Fatalf("retryAfterFromResponse with Retry-After=2 = %s, want 2s", got)
+	}
+
+	resp = &http.Response{Header: make(http.Header)}
+	if got := retryAfterFromResponse(resp); got != 0 {
+		t.Fatalf("retryAfterFromResponse with no hints = %s, want 0", got)
+	}
+}
+
+func TestIsThrottleStatus(t *testing.T) {
+	for _, code := range []int{http.StatusForbidden, http.StatusTooManyRequests, http.StatusInternalServerError} {
+		if !isThrottleStatus(code) {
+			t.Errorf("isThrottleStatus(%d) = false, want true", code)
+		}
+	}
+	if isThrottleStatus(http.StatusOK) {
+		t.Error("isThrottleStatus(200) = true, want false")
+	}
+}
+
+func TestHostLimiterWaitOnFreshHost(t *testing.T) {
+	h := NewHostLimiter(1000, time.Second)
+
+	if err := h.Wait(context.Background(), "example.com"); err != nil {
+		t.Fatalf("Wait on a fresh host should not error: %v", err)
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// ResultSink receives scan results as they arrive rather than buffering
+// until every worker finishes, so a SIGINT mid-scan still leaves a valid,
+// readable output file on disk.
+type ResultSink interface {
+	Write(Result) error
+	Close() error
+}
+
+// NewResultSink opens the sink for format ("csv", "jsonl", or "sarif") at path.
+func NewResultSink(format, path string) (ResultSink, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("creating output file: %w", err)
+	}
+	switch format {
+	case "csv":
+		return newCSVSink(f), nil
+	case "jsonl":
+		return newJSONLSink(f), nil
+	case "sarif":
+		return newSARIFSink(f), nil
+	default:
+		f.Close()
+		return nil, fmt.Errorf("unknown output format %q (want csv, jsonl, or sarif)", format)
+	}
+}
+
+// ------------------ CSV Sink ------------------ //
+type csvSink struct {
+	mu     sync.Mutex
+	file   *os.File
+	writer *csv.Writer
+}
+
+func newCSVSink(f *os.File) *csvSink {
+	w := csv.NewWriter(f)
+	w.Write([]string{"Type", "Status", "URL", "SourcePage", "Rule", "Confidence"})
+	w.Flush()
+	return &csvSink{file: f, writer: w}
+}
+
+func (s *csvSink) Write(r Result) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.writer.Write([]string{r.Type, r.Status, r.URL, r.SourcePage, r.Rule, r.Confidence}); err != nil {
+		return err
+	}
+	s.writer.Flush()
+	return s.writer.Error()
+}
+
+func (s *csvSink) Close() error {
+	s.writer.Flush()
+	return s.file.Close()
+}
+
+// ------------------ JSONL Sink ------------------ //
+type jsonlSink struct {
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+func newJSONLSink(f *os.File) *jsonlSink {
+	return &jsonlSink{file: f, enc: json.NewEncoder(f)}
+}
+
+func (s *jsonlSink) Write(r Result) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.enc.Encode(r); err != nil {
+		return err
+	}
+	return s.file.Sync()
+}
+
+func (s *jsonlSink) Close() error {
+	return s.file.Close()
+}
+
+// ------------------ SARIF Sink ------------------ //
+// SARIF 2.1.0 is a single JSON document rather than an append-friendly
+// stream, so this sink buffers findings in memory and writes the full
+// report on Close. Close still runs from the SIGINT handler, so a killed
+// scan leaves a valid (if partial) report rather than a truncated one.
+type sarifSink struct {
+	mu       sync.Mutex
+	file     *os.File
+	findings []Result
+}
+
+func newSARIFSink(f *os.File) *sarifSink {
+	return &sarifSink{file: f}
+}
+
+func (s *sarifSink) Write(r Result) error {
+	if r.Status == "ok" || r.Status == "skipped" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.findings = append(s.findings, r)
+	return nil
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	} `json:"physicalLocation"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifDriver struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type sarifRun struct {
+	Tool struct {
+		Driver sarifDriver `json:"driver"`
+	} `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+func (s *sarifSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	run := sarifRun{}
+	run.Tool.Driver = sarifDriver{Name: "tagrtol", Version: "dev"}
+	for _, r := range s.findings {
+		loc := sarifLocation{}
+		loc.PhysicalLocation.ArtifactLocation.URI = r.URL
+		run.Results = append(run.Results, sarifResult{
+			RuleID:    r.Type,
+			Level:     sarifLevel(r.Status),
+			Message:   sarifMessage{Text: fmt.Sprintf("%s: %s (found in %s)", r.Type, r.Status, r.SourcePage)},
+			Locations: []sarifLocation{loc},
+		})
+	}
+
+	doc := sarifLog{
+		Schema:  "https://raw.githubusercontent.com/oasis-tcs/sarif-spec/master/Schemata/sarif-schema-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	}
+
+	enc := json.NewEncoder(s.file)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	return s.file.Close()
+}
+
+func sarifLevel(status string) string {
+	switch status {
+	case "possible_takeover", string(ConfidenceVulnerable):
+		return "error"
+	case string(ConfidenceEdgeCase), "rate_limited", "connection_error":
+		return "warning"
+	default:
+		return "note"
+	}
+}
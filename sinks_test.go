@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewResultSinkRejectsUnknownFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out")
+	if _, err := NewResultSink("xml", path); err == nil {
+		t.Fatal("expected an error for an unknown output format")
+	}
+}
+
+func TestCSVSinkWritesHeaderAndRows(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.csv")
+	sink, err := NewResultSink("csv", path)
+	if err != nil {
+		t.Fatalf("NewResultSink: %v", err)
+	}
+
+	want := Result{Type: "heroku", Status: "vulnerable", URL: "https://app.herokuapp.com", SourcePage: "https://example.com", Rule: "heroku", Confidence: "vulnerable"}
+	if err := sink.Write(want); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("opening output: %v", err)
+	}
+	defer f.Close()
+
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatalf("reading csv: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want a header + one data row: %v", len(rows), rows)
+	}
+	if rows[0][0] != "Type" {
+		t.Fatalf("header row = %v, want it to start with Type", rows[0])
+	}
+	got := rows[1]
+	if got[0] != want.Type || got[1] != want.Status || got[2] != want.URL || got[3] != want.SourcePage {
+		t.Fatalf("data row = %v, want it to match %+v", got, want)
+	}
+}
+
+func TestJSONLSinkWritesOneObjectPerLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.jsonl")
+	sink, err := NewResultSink("jsonl", path)
+	if err != nil {
+		t.Fatalf("NewResultSink: %v", err)
+	}
+
+	results := []Result{
+		{Type: "s3", Status: "vulnerable", URL: "https://bucket.s3.amazonaws.com"},
+		{Type: "heroku", Status: "ok", URL: "https://app.herokuapp.com"},
+	}
+	for _, r := range results {
+		if err := sink.Write(r); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("opening output: %v", err)
+	}
+	defer f.Close()
+
+	var got []Result
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r Result
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("unmarshaling line %q: %v", scanner.Text(), err)
+		}
+		got = append(got, r)
+	}
+	if len(got) != len(results) {
+		t.Fatalf("got %d lines, want %d", len(got), len(results))
+	}
+	for i, r := range got {
+		if r != results[i] {
+			t.Errorf("line %d = %+v, want %+v", i, r, results[i])
+		}
+	}
+}
+
+func TestSARIFSinkOmitsNonFindingsAndSetsLevels(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.sarif")
+	sink, err := NewResultSink("sarif", path)
+	if err != nil {
+		t.Fatalf("NewResultSink: %v", err)
+	}
+
+	for _, r := range []Result{
+		{Type: "s3", Status: "ok", URL: "https://skip-me.s3.amazonaws.com"},
+		{Type: "s3", Status: "skipped", URL: "https://skip-me-too.s3.amazonaws.com"},
+		{Type: "heroku", Status: string(ConfidenceVulnerable), URL: "https://app.herokuapp.com", SourcePage: "https://example.com"},
+	} {
+		if err := sink.Write(r); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+	var doc sarifLog
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		t.Fatalf("unmarshaling sarif: %v", err)
+	}
+	if doc.Version != "2.1.0" {
+		t.Fatalf("doc.Version = %q, want 2.1.0", doc.Version)
+	}
+	if len(doc.Runs) != 1 || len(doc.Runs[0].Results) != 1 {
+		t.Fatalf("expected exactly one run with one result (ok/skipped filtered out), got %+v", doc)
+	}
+	result := doc.Runs[0].Results[0]
+	if result.Level != "error" {
+		t.Errorf("vulnerable finding level = %q, want error", result.Level)
+	}
+	if result.Locations[0].PhysicalLocation.ArtifactLocation.URI != "https://app.herokuapp.com" {
+		t.Errorf("result URI = %q, want the finding's URL", result.Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	}
+}
+
+func TestSarifLevel(t *testing.T) {
+	cases := map[string]string{
+		"possible_takeover":          "error",
+		string(ConfidenceVulnerable): "error",
+		string(ConfidenceEdgeCase):   "warning",
+		"rate_limited":               "warning",
+		"connection_error":           "warning",
+		string(ConfidenceNotVuln):    "note",
+		"ok":                         "note",
+	}
+	for status, want := range cases {
+		if got := sarifLevel(status); got != want {
+			t.Errorf("sarifLevel(%q) = %q, want %q", status, got, want)
+		}
+	}
+}
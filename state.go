@@ -0,0 +1,165 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// hashBody returns a content hash for body so the state store can tell a
+// genuinely new response apart from a byte-for-byte repeat.
+func hashBody(body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return hex.EncodeToString(sum[:])
+}
+
+var scanBucket = []byte("scans")
+
+// ScanState is what the state store remembers about a single
+// (source_page, link) pair between runs.
+type ScanState struct {
+	LastScanned time.Time `json:"last_scanned"`
+	Status      string    `json:"status"`
+	BodyHash    string    `json:"body_hash"`
+}
+
+// StateStore persists per-link scan state in BoltDB so a crash or Ctrl-C
+// doesn't lose all in-flight progress, and so a rerun can skip links that
+// were already checked recently.
+type StateStore struct {
+	db *bbolt.DB
+	mu sync.Mutex
+}
+
+// OpenStateStore opens (creating if needed) the BoltDB file at path.
+func OpenStateStore(path string) (*StateStore, error) {
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("opening state store: %w", err)
+	}
+	err = db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(scanBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("initializing state store: %w", err)
+	}
+	return &StateStore{db: db}, nil
+}
+
+func (s *StateStore) Close() error {
+	return s.db.Close()
+}
+
+func stateKey(sourcePage, link string) []byte {
+	return []byte(sourcePage + "|" + link)
+}
+
+// Get returns the previously recorded state for (sourcePage, link), if any.
+func (s *StateStore) Get(sourcePage, link string) (ScanState, bool) {
+	var st ScanState
+	found := false
+	s.db.View(func(tx *bbolt.Tx) error {
+		v := tx.Bucket(scanBucket).Get(stateKey(sourcePage, link))
+		if v == nil {
+			return nil
+		}
+		if err := json.Unmarshal(v, &st); err == nil {
+			found = true
+		}
+		return nil
+	})
+	return st, found
+}
+
+func (s *StateStore) put(sourcePage, link string, st ScanState) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(scanBucket).Put(stateKey(sourcePage, link), data)
+	})
+}
+
+// ShouldSkip reports whether (sourcePage, link) was checked within
+// recheckAfter and didn't previously fail transiently — rate-limited or
+// connection-error entries are always retried regardless of TTL.
+func (s *StateStore) ShouldSkip(sourcePage, link string, recheckAfter time.Duration) bool {
+	st, found := s.Get(sourcePage, link)
+	if !found {
+		return false
+	}
+	if st.Status == "rate_limited" || st.Status == "connection_error" {
+		return false
+	}
+	return time.Since(st.LastScanned) < recheckAfter
+}
+
+func isVulnerableStatus(status string) bool {
+	return status == "possible_takeover" || status == string(ConfidenceVulnerable)
+}
+
+// ScanDiff summarizes how results changed since the last run, so a
+// scheduled rerun can alert on what's actually new.
+type ScanDiff struct {
+	NewTakeovers    []Result `json:"new_takeovers"`
+	Resolved        []Result `json:"resolved"`
+	StillVulnerable []Result `json:"still_vulnerable"`
+}
+
+// Record classifies r's status transition against the previous run into
+// diff, then persists r's new state. Safe for concurrent use by workers.
+func (s *StateStore) Record(r Result, diff *ScanDiff) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prev, found := s.Get(r.SourcePage, r.URL)
+	wasVuln := found && isVulnerableStatus(prev.Status)
+	isVuln := isVulnerableStatus(r.Status)
+
+	switch {
+	case isVuln && !wasVuln:
+		diff.NewTakeovers = append(diff.NewTakeovers, r)
+	case !isVuln && wasVuln:
+		diff.Resolved = append(diff.Resolved, r)
+	case isVuln && wasVuln:
+		diff.StillVulnerable = append(diff.StillVulnerable, r)
+	}
+
+	s.put(r.SourcePage, r.URL, ScanState{
+		LastScanned: time.Now(),
+		Status:      r.Status,
+		BodyHash:    r.BodyHash,
+	})
+}
+
+// RecordCached classifies a cache-hit result into diff without touching its
+// LastScanned timestamp, since ShouldSkip means no request actually ran.
+// Without this, a scan interval shorter than recheckAfter would silently
+// drop every already-flagged link out of the diff report instead of
+// surfacing it as still_vulnerable.
+func (s *StateStore) RecordCached(r Result, diff *ScanDiff) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if isVulnerableStatus(r.Status) {
+		diff.StillVulnerable = append(diff.StillVulnerable, r)
+	}
+}
+
+// writeDiffReport writes diff as indented JSON to path so a cron-style rerun
+// can alert on what actually changed instead of the full result set.
+func writeDiffReport(path string, diff *ScanDiff) error {
+	data, err := json.MarshalIndent(diff, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestStateStore(t *testing.T) *StateStore {
+	t.Helper()
+	s, err := OpenStateStore(filepath.Join(t.TempDir(), "tagrtol.state"))
+	if err != nil {
+		t.Fatalf("OpenStateStore: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestShouldSkipHonorsRecheckAfter(t *testing.T) {
+	s := newTestStateStore(t)
+
+	if s.ShouldSkip("page", "link", time.Hour) {
+		t.Fatal("an unseen (page, link) pair should never be skipped")
+	}
+
+	s.Record(Result{SourcePage: "page", URL: "link", Status: "ok"}, &ScanDiff{})
+
+	if !s.ShouldSkip("page", "link", time.Hour) {
+		t.Fatal("a pair checked moments ago within the TTL should be skipped")
+	}
+	if s.ShouldSkip("page", "link", 0) {
+		t.Fatal("a zero recheckAfter should never skip")
+	}
+}
+
+func TestShouldSkipAlwaysRetriesTransientFailures(t *testing.T) {
+	s := newTestStateStore(t)
+
+	for _, status := range []string{"rate_limited", "connection_error"} {
+		s.Record(Result{SourcePage: "page", URL: status, Status: status}, &ScanDiff{})
+		if s.ShouldSkip("page", status, 24*time.Hour) {
+			t.Errorf("a %q result should always be rechecked, never skipped", status)
+		}
+	}
+}
+
+func TestRecordClassifiesDiffTransitions(t *testing.T) {
+	s := newTestStateStore(t)
+
+	diff := &ScanDiff{}
+	s.Record(Result{SourcePage: "page", URL: "a", Status: "ok"}, diff)
+	if len(diff.NewTakeovers) != 0 {
+		t.Fatalf("a non-vulnerable first sighting should not be a new takeover: %+v", diff)
+	}
+
+	diff = &ScanDiff{}
+	s.Record(Result{SourcePage: "page", URL: "a", Status: string(ConfidenceVulnerable)}, diff)
+	if len(diff.NewTakeovers) != 1 {
+		t.Fatalf("ok -> vulnerable should report a new takeover, got %+v", diff)
+	}
+
+	diff = &ScanDiff{}
+	s.Record(Result{SourcePage: "page", URL: "a", Status: string(ConfidenceVulnerable)}, diff)
+	if len(diff.StillVulnerable) != 1 {
+		t.Fatalf("vulnerable -> vulnerable should report still-vulnerable, got %+v", diff)
+	}
+
+	diff = &ScanDiff{}
+	s.Record(Result{SourcePage: "page", URL: "a", Status: "ok"}, diff)
+	if len(diff.Resolved) != 1 {
+		t.Fatalf("vulnerable -> ok should report resolved, got %+v", diff)
+	}
+}
+
+func TestRecordCachedClassifiesWithoutResettingTTL(t *testing.T) {
+	s := newTestStateStore(t)
+
+	diff := &ScanDiff{}
+	s.RecordCached(Result{SourcePage: "page", URL: "a", Status: string(ConfidenceVulnerable)}, diff)
+	if len(diff.StillVulnerable) != 1 {
+		t.Fatalf("a cached vulnerable result should report still_vulnerable, got %+v", diff)
+	}
+
+	diff = &ScanDiff{}
+	s.RecordCached(Result{SourcePage: "page", URL: "a", Status: "ok"}, diff)
+	if len(diff.StillVulnerable) != 0 || len(diff.NewTakeovers) != 0 || len(diff.Resolved) != 0 {
+		t.Fatalf("a cached non-vulnerable result should not appear in any diff bucket, got %+v", diff)
+	}
+
+	if _, found := s.Get("page", "a"); found {
+		t.Fatal("RecordCached must not persist state — it's a cache hit, not a new observation")
+	}
+}
+
+// TestWorkerSurfacesCacheHitsAsStillVulnerable is a regression test for the
+// worker()/ShouldSkip integration gap: a link skipped because it was already
+// checked within --recheck-after must still show up in the diff report if it
+// was previously vulnerable, or a cron interval shorter than recheckAfter
+// would silently drop it from every run's alert.
+func TestWorkerSurfacesCacheHitsAsStillVulnerable(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<a href="/target">target</a>`))
+	}))
+	defer srv.Close()
+
+	origClient := httpClient
+	httpClient = srv.Client()
+	defer func() { httpClient = origClient }()
+
+	target := srv.URL + "/target"
+
+	state := newTestStateStore(t)
+	state.Record(Result{SourcePage: srv.URL, URL: target, Status: string(ConfidenceVulnerable)}, &ScanDiff{})
+
+	limiter := NewHostLimiter(1000, time.Second)
+	crawler := NewCrawler(0, nil, false, limiter)
+	engine := &FingerprintEngine{}
+	resolver := NewDNSResolver(nil, time.Second)
+	log := NewLogger(LogError)
+	progress := NewScanProgress(1)
+	diff := &ScanDiff{}
+
+	jobs := make(chan string, 1)
+	results := make(chan Result, 10)
+	jobs <- srv.URL
+	close(jobs)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go worker(context.Background(), &wg, jobs, results, engine, resolver, limiter, 0, log, progress, crawler, state, time.Hour, diff)
+	wg.Wait()
+	close(results)
+
+	for range results {
+	}
+
+	if len(diff.StillVulnerable) != 1 {
+		t.Fatalf("expected the cached vulnerable link to be reported as still_vulnerable, got %+v", diff)
+	}
+	if diff.StillVulnerable[0].URL != target {
+		t.Fatalf("still_vulnerable entry = %+v, want URL %q", diff.StillVulnerable[0], target)
+	}
+}
+
+func TestHashBodyIsStableAndSensitiveToContent(t *testing.T) {
+	if hashBody("hello") != hashBody("hello") {
+		t.Fatal("hashBody should be deterministic for identical input")
+	}
+	if hashBody("hello") == hashBody("world") {
+		t.Fatal("hashBody should differ for different input")
+	}
+}
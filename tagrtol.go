@@ -2,17 +2,17 @@ package main
 
 import (
 	"bufio"
-	"encoding/csv"
+	"context"
+	"flag"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
 	"strings"
 	"sync"
 	"time"
-
-	"golang.org/x/net/html"
 )
 
 type Result struct {
@@ -20,6 +20,9 @@ type Result struct {
 	Status     string
 	URL        string
 	SourcePage string
+	Rule       string
+	Confidence string
+	BodyHash   string
 }
 
 var (
@@ -28,39 +31,6 @@ var (
 	githubToken = os.Getenv("GITHUB_TOKEN")
 )
 
-// ------------------ Link Extraction ------------------ //
-func extractLinks(site string) []string {
-	resp, err := httpClient.Get(site)
-	if err != nil {
-		return nil
-	}
-	defer resp.Body.Close()
-
-	links := []string{}
-	tokenizer := html.NewTokenizer(resp.Body)
-	for {
-		tt := tokenizer.Next()
-		if tt == html.ErrorToken {
-			break
-		}
-		token := tokenizer.Token()
-		if token.Data == "a" {
-			for _, attr := range token.Attr {
-				if attr.Key == "href" {
-					u, err := url.Parse(attr.Val)
-					if err == nil {
-						if !u.IsAbs() {
-							u = resp.Request.URL.ResolveReference(u)
-						}
-						links = append(links, u.String())
-					}
-				}
-			}
-		}
-	}
-	return links
-}
-
 func readBody(resp *http.Response) string {
 	buf := new(strings.Builder)
 	io.Copy(buf, resp.Body)
@@ -68,42 +38,63 @@ func readBody(resp *http.Response) string {
 }
 
 // ------------------ GitHub Support ------------------ //
-func checkGitHub(link string) Result {
+// GitHub is handled separately from the fingerprint engine: takeover
+// candidates are resolved through the GitHub API rather than a body/status
+// match, and the path needs splitting into owner/repo/gist/user segments.
+func checkGitHub(ctx context.Context, link string, limiter Limiter) Result {
 	parsed, _ := url.Parse(link)
 	parts := strings.Split(strings.Trim(parsed.Path, "/"), "/")
 
 	if strings.Contains(link, "github.io") {
+		host := linkHost(link)
+		if err := limiter.Wait(ctx, host); err != nil {
+			return Result{Type: "github_pages", Status: "connection_error", URL: link}
+		}
 		resp, err := httpClient.Get(link)
 		if err != nil {
-			return Result{"github_pages", "connection_error", link, ""}
+			return Result{Type: "github_pages", Status: "connection_error", URL: link}
 		}
 		defer resp.Body.Close()
+		if isThrottleStatus(resp.StatusCode) {
+			limiter.Throttle(host, retryAfterFromResponse(resp))
+			return Result{Type: "github_pages", Status: "rate_limited", URL: link}
+		}
+		limiter.Reset(host)
 		body := readBody(resp)
 		if strings.Contains(body, "There isn't a GitHub Pages site here.") {
-			return Result{"github_pages", "possible_takeover", link, ""}
+			return Result{Type: "github_pages", Status: "possible_takeover", URL: link, BodyHash: hashBody(body)}
 		}
-		return Result{"github_pages", "ok", link, ""}
+		return Result{Type: "github_pages", Status: "ok", URL: link, BodyHash: hashBody(body)}
 	}
 
 	if strings.Contains(link, "gist.github.com") && len(parts) >= 2 {
 		api := fmt.Sprintf("https://api.github.com/gists/%s", parts[len(parts)-1])
-		return githubAPIRequest("gist", api, link)
+		return githubAPIRequest(ctx, "gist", api, link, limiter)
 	}
 
 	if len(parts) >= 2 {
 		api := fmt.Sprintf("https://api.github.com/repos/%s/%s", parts[0], parts[1])
-		return githubAPIRequest("repo", api, link)
+		return githubAPIRequest(ctx, "repo", api, link, limiter)
 	}
 
 	if len(parts) == 1 && parts[0] != "" {
 		api := fmt.Sprintf("https://api.github.com/users/%s", parts[0])
-		return githubAPIRequest("user", api, link)
+		return githubAPIRequest(ctx, "user", api, link, limiter)
 	}
 
-	return Result{"github", "invalid_url", link, ""}
+	return Result{Type: "github", Status: "invalid_url", URL: link}
 }
 
-func githubAPIRequest(entityType, api, link string) Result {
+// githubAPIRequest shares the limiter with the fingerprint engine, but
+// throttles on "api.github.com" specifically so GitHub's own rate-limit
+// headers (X-RateLimit-Remaining, Retry-After) don't get conflated with the
+// backoff state of whatever host github.com links point at.
+func githubAPIRequest(ctx context.Context, entityType, api, link string, limiter Limiter) Result {
+	const githubAPIHost = "api.github.com"
+	if err := limiter.Wait(ctx, githubAPIHost); err != nil {
+		return Result{Type: entityType, Status: "connection_error", URL: link}
+	}
+
 	req, _ := http.NewRequest("GET", api, nil)
 	req.Header.Set("User-Agent", "Takeover-Scanner")
 	if githubToken != "" {
@@ -111,193 +102,184 @@ func githubAPIRequest(entityType, api, link string) Result {
 	}
 	resp, err := httpClient.Do(req)
 	if err != nil {
-		return Result{entityType, "connection_error", link, ""}
+		return Result{Type: entityType, Status: "connection_error", URL: link}
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode == 404 {
-		return Result{entityType, "not_found", link, ""}
+		limiter.Reset(githubAPIHost)
+		return Result{Type: entityType, Status: "not_found", URL: link}
 	} else if resp.StatusCode == 200 {
-		return Result{entityType, "exists", link, ""}
-	} else if resp.StatusCode == 403 || resp.StatusCode == 429 {
-		return Result{entityType, "rate_limited", link, ""}
+		limiter.Reset(githubAPIHost)
+		return Result{Type: entityType, Status: "exists", URL: link}
+	} else if isThrottleStatus(resp.StatusCode) {
+		limiter.Throttle(githubAPIHost, retryAfterFromResponse(resp))
+		return Result{Type: entityType, Status: "rate_limited", URL: link}
 	} else {
-		return Result{entityType, fmt.Sprintf("error_%d", resp.StatusCode), link, ""}
-	}
-}
-
-// ------------------ Other Services ------------------ //
-func checkS3(link string) Result {
-	resp, err := httpClient.Get(link)
-	if err != nil {
-		return Result{"s3", "connection_error", link, ""}
-	}
-	defer resp.Body.Close()
-	body := readBody(resp)
-	if strings.Contains(body, "NoSuchBucket") {
-		return Result{"s3", "possible_takeover", link, ""}
-	}
-	return Result{"s3", "ok", link, ""}
-}
-
-func checkHeroku(link string) Result {
-	resp, err := httpClient.Get(link)
-	if err != nil {
-		return Result{"heroku", "connection_error", link, ""}
-	}
-	defer resp.Body.Close()
-	body := readBody(resp)
-	if strings.Contains(body, "no such app") {
-		return Result{"heroku", "possible_takeover", link, ""}
-	}
-	return Result{"heroku", "ok", link, ""}
-}
-
-func checkVercel(link string) Result {
-	resp, err := httpClient.Get(link)
-	if err != nil {
-		return Result{"vercel", "connection_error", link, ""}
-	}
-	defer resp.Body.Close()
-	body := readBody(resp)
-	if strings.Contains(body, "Vercel") && strings.Contains(body, "404") {
-		return Result{"vercel", "possible_takeover", link, ""}
-	}
-	return Result{"vercel", "ok", link, ""}
-}
-
-func checkNetlify(link string) Result {
-	resp, err := httpClient.Get(link)
-	if err != nil {
-		return Result{"netlify", "connection_error", link, ""}
-	}
-	defer resp.Body.Close()
-	body := readBody(resp)
-	if strings.Contains(body, "Not Found") && strings.Contains(body, "netlify") {
-		return Result{"netlify", "possible_takeover", link, ""}
+		return Result{Type: entityType, Status: fmt.Sprintf("error_%d", resp.StatusCode), URL: link}
 	}
-	return Result{"netlify", "ok", link, ""}
 }
 
-func checkChromeExtension(link string) Result {
+// ------------------ Chrome Web Store Support ------------------ //
+// Like GitHub, Chrome extensions need their ID pulled out of the URL path
+// before a lookup, so they stay outside the generic fingerprint engine.
+func checkChromeExtension(ctx context.Context, link string, limiter Limiter) Result {
 	u, err := url.Parse(link)
 	if err != nil {
-		return Result{"chrome_ext", "invalid_url", link, ""}
+		return Result{Type: "chrome_ext", Status: "invalid_url", URL: link}
 	}
 	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
 	if len(parts) < 2 {
-		return Result{"chrome_ext", "invalid_url", link, ""}
+		return Result{Type: "chrome_ext", Status: "invalid_url", URL: link}
 	}
 	extID := parts[len(parts)-1]
 
 	checkURL := "https://chrome.google.com/webstore/detail/" + extID
+	host := linkHost(checkURL)
+	if err := limiter.Wait(ctx, host); err != nil {
+		return Result{Type: "chrome_ext", Status: "connection_error", URL: link}
+	}
 	resp, err := httpClient.Get(checkURL)
 	if err != nil {
-		return Result{"chrome_ext", "connection_error", link, ""}
+		return Result{Type: "chrome_ext", Status: "connection_error", URL: link}
 	}
 	defer resp.Body.Close()
-	body := readBody(resp)
-	if strings.Contains(body, "Item not found") || strings.Contains(body, "404") {
-		return Result{"chrome_ext", "not_found", link, ""}
-	}
-	return Result{"chrome_ext", "exists", link, ""}
-}
-
-func checkWix(link string) Result {
-	resp, err := httpClient.Get(link)
-	if err != nil {
-		return Result{"wix", "connection_error", link, ""}
+	if isThrottleStatus(resp.StatusCode) {
+		limiter.Throttle(host, retryAfterFromResponse(resp))
+		return Result{Type: "chrome_ext", Status: "rate_limited", URL: link}
 	}
-	defer resp.Body.Close()
+	limiter.Reset(host)
 	body := readBody(resp)
-	if strings.Contains(body, "domain isn’t connected to a website") || strings.Contains(body, "Looks like this domain") {
-		return Result{"wix", "possible_takeover", link, ""}
+	if strings.Contains(body, "Item not found") || strings.Contains(body, "404") {
+		return Result{Type: "chrome_ext", Status: "not_found", URL: link, BodyHash: hashBody(body)}
 	}
-	return Result{"wix", "ok", link, ""}
+	return Result{Type: "chrome_ext", Status: "exists", URL: link, BodyHash: hashBody(body)}
 }
 
-func checkTumblr(link string) Result {
-	resp, err := httpClient.Get(link)
-	if err != nil {
-		return Result{"tumblr", "connection_error", link, ""}
-	}
-	defer resp.Body.Close()
-	body := readBody(resp)
-	if strings.Contains(body, "There's nothing here") || resp.StatusCode == 404 {
-		return Result{"tumblr", "possible_takeover", link, ""}
+// ------------------ Dispatcher ------------------ //
+// detectService handles the services that need bespoke request logic, then
+// falls through to the fingerprint engine for everything data-driven.
+// cnameTarget is the link's resolved CNAME (if DNS resolution ran), so a
+// link like docs.example.com that CNAMEs to example.herokuapp.com is caught
+// even though the URL itself never mentions heroku.
+func detectService(ctx context.Context, link, cnameTarget string, engine *FingerprintEngine, limiter Limiter) Result {
+	switch {
+	case strings.Contains(link, "github.com"), strings.Contains(link, "github.io"), strings.Contains(link, "gist.github.com"):
+		return checkGitHub(ctx, link, limiter)
+	case strings.Contains(link, "chrome.google.com/webstore/detail/"):
+		return checkChromeExtension(ctx, link, limiter)
+	default:
+		return engine.Detect(ctx, link, cnameTarget, limiter)
 	}
-	return Result{"tumblr", "ok", link, ""}
 }
 
-func checkShopify(link string) Result {
-	resp, err := httpClient.Get(link)
+func linkHost(link string) string {
+	u, err := url.Parse(link)
 	if err != nil {
-		return Result{"shopify", "connection_error", link, ""}
-	}
-	defer resp.Body.Close()
-	body := readBody(resp)
-	if strings.Contains(body, "store is unavailable") || strings.Contains(body, "This store is unavailable") {
-		return Result{"shopify", "possible_takeover", link, ""}
+		return ""
 	}
-	return Result{"shopify", "ok", link, ""}
+	return u.Hostname()
 }
 
-// ------------------ Dispatcher ------------------ //
-func detectService(link string) Result {
-	switch {
-	case strings.Contains(link, "github.com"), strings.Contains(link, "github.io"), strings.Contains(link, "gist.github.com"):
-		return checkGitHub(link)
-	case strings.Contains(link, ".s3.amazonaws.com"):
-		return checkS3(link)
-	case strings.Contains(link, "herokuapp.com"):
-		return checkHeroku(link)
-	case strings.Contains(link, ".vercel.app"):
-		return checkVercel(link)
-	case strings.Contains(link, ".netlify.app"):
-		return checkNetlify(link)
-	case strings.Contains(link, "chrome.google.com/webstore/detail/"):
-		return checkChromeExtension(link)
-	case strings.Contains(link, ".wixsite.com"):
-		return checkWix(link)
-	case strings.Contains(link, ".tumblr.com"):
-		return checkTumblr(link)
-	case strings.Contains(link, ".myshopify.com"):
-		return checkShopify(link)
-	default:
-		return Result{"unknown", "skipped", link, ""}
+// processLink runs detectService for a single link, requeuing it against the
+// limiter's backoff instead of giving up the moment a host starts throttling.
+// It only gives up once maxRetries is exhausted or the scan is cancelled.
+func processLink(ctx context.Context, l, cnameTarget string, engine *FingerprintEngine, limiter Limiter, maxRetries int, log *Logger) Result {
+	var r Result
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		r = detectService(ctx, l, cnameTarget, engine, limiter)
+		if r.Status != "rate_limited" {
+			return r
+		}
+		if ctx.Err() != nil {
+			return r
+		}
+		log.Warnf("requeueing %s after rate limit (attempt %d/%d)", l, attempt+1, maxRetries)
 	}
+	return r
 }
 
 // ------------------ Worker ------------------ //
-func worker(wg *sync.WaitGroup, jobs <-chan string, results chan<- Result) {
+func worker(ctx context.Context, wg *sync.WaitGroup, jobs <-chan string, results chan<- Result, engine *FingerprintEngine, resolver *DNSResolver, limiter Limiter, maxRetries int, log *Logger, progress *ScanProgress, crawler *Crawler, state *StateStore, recheckAfter time.Duration, diff *ScanDiff) {
 	defer wg.Done()
 	for site := range jobs {
-		fmt.Println("[*] Scanning site:", site)
-		links := extractLinks(site)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Infof("Scanning site: %s", site)
+		links := crawler.Crawl(ctx, site)
 		for _, l := range links {
-			r := detectService(l)
+			if ctx.Err() != nil {
+				return
+			}
+			if state != nil && state.ShouldSkip(site, l, recheckAfter) {
+				prev, _ := state.Get(site, l)
+				log.Debugf("skipping %s (rechecked within %s)", l, recheckAfter)
+				cached := Result{Type: "cached", Status: prev.Status, URL: l, SourcePage: site, BodyHash: prev.BodyHash}
+				state.RecordCached(cached, diff)
+				results <- cached
+				continue
+			}
+			var cnameTarget string
+			if host := linkHost(l); host != "" {
+				cnameTarget = resolver.Resolve(host, engine, limiter).CNAME
+			}
+			r := processLink(ctx, l, cnameTarget, engine, limiter, maxRetries, log)
 			r.SourcePage = site
+			if state != nil {
+				state.Record(r, diff)
+			}
 			if r.Type != "unknown" {
 				switch r.Status {
-				case "possible_takeover":
-					fmt.Printf("  \033[31m→ [%s] %s: %s (found in: %s)\033[0m\n", strings.ToUpper(r.Type), r.Status, r.URL, r.SourcePage)
-				case "rate_limited":
-					fmt.Printf("  \033[33m→ [%s] %s: %s\033[0m\n", strings.ToUpper(r.Type), r.Status, r.URL)
+				case "possible_takeover", string(ConfidenceVulnerable):
+					log.Warnf("[%s] %s: %s (found in: %s)", strings.ToUpper(r.Type), r.Status, r.URL, r.SourcePage)
+				case "rate_limited", "connection_error":
+					log.Warnf("[%s] %s: %s", strings.ToUpper(r.Type), r.Status, r.URL)
 				default:
-					fmt.Printf("  → [%s] %s: %s\n", strings.ToUpper(r.Type), r.Status, r.URL)
+					log.Debugf("[%s] %s: %s", strings.ToUpper(r.Type), r.Status, r.URL)
 				}
 				results <- r
 			}
-			time.Sleep(100 * time.Millisecond)
 		}
+		progress.SiteScanned()
 	}
 }
 
 // ------------------ Main ------------------ //
 func main() {
+	dnsOnly := flag.Bool("dns-only", false, "skip HTTP fingerprinting and just report dangling DNS records for the hosts in sites.txt")
+	dnsResolvers := flag.String("dns-resolvers", "", "comma-separated resolver addresses to use instead of the system default (e.g. 1.1.1.1:53,8.8.8.8:53)")
+	dnsTimeout := flag.Duration("dns-timeout", 5*time.Second, "timeout for each DNS lookup")
+	logLevel := flag.String("log-level", "info", "log verbosity: debug, info, warn, or error")
+	outputFormat := flag.String("output-format", "csv", "result sink format: csv, jsonl, or sarif")
+	outputPath := flag.String("output", "results.csv", "path to write scan results to")
+	rpsPerHost := flag.Float64("rps-per-host", 10, "max requests per second to any single host")
+	maxRetries := flag.Int("max-retries", 5, "max times to requeue a link that hit a rate limit before giving up")
+	backoffMax := flag.Duration("backoff-max", 10*time.Second, "ceiling for the exponential backoff applied to a throttled host")
+	depth := flag.Int("depth", 1, "how many link-hops beyond each seed site to crawl")
+	allowHosts := flag.String("allow-hosts", "", "comma-separated hosts the crawler may follow besides the seed's own origin (default: same-origin only)")
+	useSitemap := flag.Bool("sitemap", false, "seed the crawler from /sitemap.xml and the robots.txt Sitemap: directives")
+	stateFile := flag.String("state-file", "tagrtol.state", "BoltDB file used to resume scans and skip recently-checked links")
+	recheckAfter := flag.Duration("recheck-after", 24*time.Hour, "skip a (source_page, link) pair that was already checked within this long, unless it was previously rate_limited or connection_error")
+	flag.Parse()
+
+	log := NewLogger(parseLogLevel(*logLevel))
+
+	engine, err := LoadFingerprints("fingerprints.yaml")
+	if err != nil {
+		log.Errorf("loading fingerprints.yaml: %v", err)
+		return
+	}
+
+	var resolverServers []string
+	if *dnsResolvers != "" {
+		resolverServers = strings.Split(*dnsResolvers, ",")
+	}
+	resolver := NewDNSResolver(resolverServers, *dnsTimeout)
+
 	file, err := os.Open("sites.txt")
 	if err != nil {
-		fmt.Println("Error opening sites.txt:", err)
+		log.Errorf("opening sites.txt: %v", err)
 		return
 	}
 	defer file.Close()
@@ -311,59 +293,102 @@ func main() {
 		}
 	}
 
+	limiter := NewHostLimiter(*rpsPerHost, *backoffMax)
+
+	var allowedHosts []string
+	if *allowHosts != "" {
+		allowedHosts = strings.Split(*allowHosts, ",")
+	}
+	crawler := NewCrawler(*depth, allowedHosts, *useSitemap, limiter)
+
+	if *dnsOnly {
+		dnsOnlyScan(sites, resolver, engine, limiter, log)
+		return
+	}
+
+	state, err := OpenStateStore(*stateFile)
+	if err != nil {
+		log.Errorf("opening state store: %v", err)
+		return
+	}
+	defer state.Close()
+	diff := &ScanDiff{}
+
+	sink, err := NewResultSink(*outputFormat, *outputPath)
+	if err != nil {
+		log.Errorf("opening result sink: %v", err)
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	progress := NewScanProgress(len(sites))
+
 	jobs := make(chan string, len(sites))
 	resultsChan := make(chan Result, 100)
 
 	var wg sync.WaitGroup
 	for i := 0; i < maxWorkers; i++ {
 		wg.Add(1)
-		go worker(&wg, jobs, resultsChan)
+		go worker(ctx, &wg, jobs, resultsChan, engine, resolver, limiter, *maxRetries, log, progress, crawler, state, *recheckAfter, diff)
 	}
 
-	for _, site := range sites {
-		jobs <- site
-	}
-	close(jobs)
+	go func() {
+		for _, site := range sites {
+			select {
+			case jobs <- site:
+			case <-ctx.Done():
+				close(jobs)
+				return
+			}
+		}
+		close(jobs)
+	}()
 
 	go func() {
 		wg.Wait()
 		close(resultsChan)
 	}()
 
-	var results []Result
+	var takeovers []Result
 	for r := range resultsChan {
-		results = append(results, r)
+		if err := sink.Write(r); err != nil {
+			log.Errorf("writing result to sink: %v", err)
+		}
+		progress.LinkChecked(r)
+		if r.Status == "possible_takeover" || r.Status == string(ConfidenceVulnerable) {
+			takeovers = append(takeovers, r)
+		}
 	}
+	progress.Finish()
 
-	csvFile, err := os.Create("results.csv")
-	if err != nil {
-		fmt.Println("Error creating results.csv:", err)
-		return
+	if err := sink.Close(); err != nil {
+		log.Errorf("closing result sink: %v", err)
 	}
-	defer csvFile.Close()
-
-	writer := csv.NewWriter(csvFile)
-	writer.Write([]string{"Type", "Status", "URL", "SourcePage"})
-	for _, r := range results {
-		writer.Write([]string{r.Type, r.Status, r.URL, r.SourcePage})
+	if ctx.Err() != nil {
+		log.Warnf("interrupted — partial results flushed to %s", *outputPath)
+	} else {
+		log.Infof("Scan completed. Results saved to %s", *outputPath)
 	}
-	writer.Flush()
 
-	fmt.Println("\n✅ Scan completed. Results saved to results.csv")
-
-	// ✅ Save only takeovers to takeovers.txt
 	takeoverFile, err := os.Create("takeovers.txt")
 	if err != nil {
-		fmt.Println("Error creating takeovers.txt:", err)
+		log.Errorf("creating takeovers.txt: %v", err)
 		return
 	}
 	defer takeoverFile.Close()
 
-	for _, r := range results {
-		if r.Status == "possible_takeover" {
-			takeoverFile.WriteString(fmt.Sprintf("%s (found in: %s)\n", r.URL, r.SourcePage))
-		}
+	for _, r := range takeovers {
+		takeoverFile.WriteString(fmt.Sprintf("%s (found in: %s)\n", r.URL, r.SourcePage))
 	}
 
-	fmt.Println("✅ Only takeovers saved to takeovers.txt")
+	log.Infof("Only takeovers saved to takeovers.txt")
+
+	if err := writeDiffReport("diff_report.json", diff); err != nil {
+		log.Errorf("writing diff_report.json: %v", err)
+	} else {
+		log.Infof("Change report: %d new, %d resolved, %d still vulnerable (diff_report.json)",
+			len(diff.NewTakeovers), len(diff.Resolved), len(diff.StillVulnerable))
+	}
 }